@@ -0,0 +1,97 @@
+package dpapi
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncryptDecryptPathDirectory(t *testing.T) {
+	srcDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(srcDir, "a.txt"), []byte("file a"), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(srcDir, "sub"), 0755); err != nil {
+		t.Fatalf("failed to create subdirectory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "sub", "b.txt"), []byte("file b"), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	encFile := filepath.Join(t.TempDir(), "archive.enc")
+	if err := EncryptPath(srcDir, encFile); err != nil {
+		t.Fatalf("failed to encrypt directory: %v", err)
+	}
+
+	dstDir := t.TempDir()
+	if err := DecryptPath(encFile, dstDir); err != nil {
+		t.Fatalf("failed to decrypt directory: %v", err)
+	}
+
+	gotA, err := os.ReadFile(filepath.Join(dstDir, "a.txt"))
+	if err != nil {
+		t.Fatalf("failed to read extracted file a.txt: %v", err)
+	}
+	if !bytes.Equal(gotA, []byte("file a")) {
+		t.Errorf("a.txt content mismatch: got %q", gotA)
+	}
+
+	gotB, err := os.ReadFile(filepath.Join(dstDir, "sub", "b.txt"))
+	if err != nil {
+		t.Fatalf("failed to read extracted file sub/b.txt: %v", err)
+	}
+	if !bytes.Equal(gotB, []byte("file b")) {
+		t.Errorf("sub/b.txt content mismatch: got %q", gotB)
+	}
+}
+
+func TestExtractZipRejectsPathTraversal(t *testing.T) {
+	var zipData bytes.Buffer
+	zw := zip.NewWriter(&zipData)
+	entry, err := zw.Create("../evil.txt")
+	if err != nil {
+		t.Fatalf("failed to create archive entry: %v", err)
+	}
+	if _, err := entry.Write([]byte("escaped")); err != nil {
+		t.Fatalf("failed to write archive entry: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to close zip writer: %v", err)
+	}
+
+	dstDir := t.TempDir()
+	if err := extractZip(zipData.Bytes(), dstDir); err == nil {
+		t.Fatal("expected error extracting an archive entry with a path-traversal name, but got nil")
+	}
+
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dstDir), "evil.txt")); !os.IsNotExist(err) {
+		t.Error("expected no file to be written outside dstDir")
+	}
+}
+
+func TestEncryptDecryptPathSingleFile(t *testing.T) {
+	srcFile := filepath.Join(t.TempDir(), "plain.txt")
+	if err := os.WriteFile(srcFile, []byte("just a file"), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	encFile := filepath.Join(t.TempDir(), "plain.enc")
+	if err := EncryptPath(srcFile, encFile); err != nil {
+		t.Fatalf("failed to encrypt file: %v", err)
+	}
+
+	dstDir := t.TempDir()
+	if err := DecryptPath(encFile, dstDir); err != nil {
+		t.Fatalf("failed to decrypt file: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dstDir, filepath.Base(encFile)))
+	if err != nil {
+		t.Fatalf("failed to read decrypted file: %v", err)
+	}
+	if !bytes.Equal(got, []byte("just a file")) {
+		t.Errorf("decrypted file content mismatch: got %q", got)
+	}
+}
@@ -0,0 +1,84 @@
+package dpapi
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestEncryptFileWritesAuthenticatedContainer(t *testing.T) {
+	inputFile, err := os.CreateTemp("", "testfile*.txt")
+	if err != nil {
+		t.Fatalf("failed to create temporary input file: %v", err)
+	}
+	defer os.Remove(inputFile.Name())
+	inputFile.WriteString("authenticated container contents")
+	inputFile.Close()
+
+	outputFile := inputFile.Name() + ".enc"
+	if err := EncryptFile(inputFile.Name(), outputFile); err != nil {
+		t.Fatalf("failed to encrypt file: %v", err)
+	}
+	defer os.Remove(outputFile)
+
+	data, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("failed to read encrypted file: %v", err)
+	}
+	if len(data) < len(fileMagic) || string(data[:len(fileMagic)]) != fileMagic {
+		t.Fatalf("expected encrypted file to start with the DPAPI file magic")
+	}
+}
+
+func TestDecryptFileRejectsNonDPAPIFile(t *testing.T) {
+	inputFile, err := os.CreateTemp("", "notdpapi*.txt")
+	if err != nil {
+		t.Fatalf("failed to create temporary input file: %v", err)
+	}
+	defer os.Remove(inputFile.Name())
+	inputFile.WriteString("just a plain text file, never encrypted")
+	inputFile.Close()
+
+	outputFile := inputFile.Name() + ".dec"
+	err = DecryptFile(inputFile.Name(), outputFile)
+	if err == nil {
+		t.Fatal("expected error decrypting a non-DPAPI file, but got nil")
+	}
+	if !errors.Is(err, ErrNotDPAPIFile) {
+		t.Errorf("expected ErrNotDPAPIFile, got %v", err)
+	}
+}
+
+func TestDecryptFileRejectsTamperedFile(t *testing.T) {
+	inputFile, err := os.CreateTemp("", "testfile*.txt")
+	if err != nil {
+		t.Fatalf("failed to create temporary input file: %v", err)
+	}
+	defer os.Remove(inputFile.Name())
+	inputFile.WriteString("some data that will be tampered with after encryption")
+	inputFile.Close()
+
+	outputFile := inputFile.Name() + ".enc"
+	if err := EncryptFile(inputFile.Name(), outputFile); err != nil {
+		t.Fatalf("failed to encrypt file: %v", err)
+	}
+	defer os.Remove(outputFile)
+
+	data, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("failed to read encrypted file: %v", err)
+	}
+	data[len(data)-1] ^= 0xFF // corrupt the trailing MAC byte
+	if err := os.WriteFile(outputFile, data, 0644); err != nil {
+		t.Fatalf("failed to write tampered file: %v", err)
+	}
+
+	decryptedFile := inputFile.Name() + ".dec"
+	err = DecryptFile(outputFile, decryptedFile)
+	if err == nil {
+		t.Fatal("expected error decrypting a tampered file, but got nil")
+	}
+	if !errors.Is(err, ErrTampered) {
+		t.Errorf("expected ErrTampered, got %v", err)
+	}
+}
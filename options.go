@@ -0,0 +1,165 @@
+package dpapi
+
+import (
+	"fmt"
+	"log"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Scope selects whether DPAPI protects data for the local machine (any user
+// with access to the machine can unprotect it) or only for the current user
+// account.
+type Scope int
+
+const (
+	// ScopeCurrentUser ties protection to the calling user's account. This
+	// is DPAPI's own default when CRYPTPROTECT_LOCAL_MACHINE is not set.
+	ScopeCurrentUser Scope = iota
+	// ScopeMachine ties protection to the local machine; any user on the
+	// machine can unprotect it. EncryptDPAPI/DecryptDPAPI use this scope.
+	ScopeMachine
+)
+
+// Options configures a single DPAPI protect/unprotect call beyond the
+// defaults used by EncryptDPAPI/DecryptDPAPI.
+type Options struct {
+	// Scope selects user- or machine-scope protection.
+	Scope Scope
+	// Entropy is optional secondary entropy mixed into protection. The same
+	// bytes must be supplied to both EncryptDPAPIWithOptions and the
+	// matching DecryptDPAPIWithOptions call, or decryption fails.
+	Entropy []byte
+	// Description is stored alongside the ciphertext and returned by
+	// DecryptDPAPIWithOptions.
+	Description string
+	// UIForbidden sets CRYPTPROTECT_UI_FORBIDDEN, so DPAPI fails instead of
+	// showing a UI prompt (e.g. during credential roaming).
+	UIForbidden bool
+	// Audit sets CRYPTPROTECT_AUDIT, asking DPAPI to write an audit log
+	// entry for the operation.
+	Audit bool
+}
+
+// flags translates opts into the CRYPTPROTECT_* flag bits CryptProtectData
+// and CryptUnprotectData expect.
+func (o Options) flags() uint32 {
+	var flags uint32
+	if o.Scope == ScopeMachine {
+		flags |= windows.CRYPTPROTECT_LOCAL_MACHINE
+	}
+	if o.UIForbidden {
+		flags |= windows.CRYPTPROTECT_UI_FORBIDDEN
+	}
+	if o.Audit {
+		flags |= windows.CRYPTPROTECT_AUDIT
+	}
+	return flags
+}
+
+// entropyBlob wraps o.Entropy as a windows.DataBlob, or returns nil when no
+// secondary entropy was configured.
+func (o Options) entropyBlob() *windows.DataBlob {
+	if len(o.Entropy) == 0 {
+		return nil
+	}
+	return &windows.DataBlob{
+		Size: uint32(len(o.Entropy)),
+		Data: &o.Entropy[0],
+	}
+}
+
+// EncryptDPAPIWithOptions encrypts data using DPAPI with the scope, secondary
+// entropy, description, and flags described by opts. It returns the
+// encrypted data and any error encountered.
+func EncryptDPAPIWithOptions(data []byte, opts Options) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("input data cannot be empty")
+	}
+
+	if verboseLogging {
+		log.Println("Encrypting data using DPAPI with options...")
+	}
+
+	desc := windows.StringToUTF16Ptr(opts.Description)
+	inBlob := windows.DataBlob{
+		Size: uint32(len(data)),
+		Data: &data[0],
+	}
+	var outBlob windows.DataBlob
+
+	err := windows.CryptProtectData(
+		&inBlob,
+		desc,
+		opts.entropyBlob(),
+		0,
+		nil,
+		opts.flags(),
+		&outBlob,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt data: %w", err)
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(outBlob.Data)))
+
+	// Create a copy of the encrypted data
+	encrypted := make([]byte, outBlob.Size)
+	copy(encrypted, unsafe.Slice(outBlob.Data, outBlob.Size))
+
+	if verboseLogging {
+		log.Println("Data encryption successful.")
+	}
+	return encrypted, nil
+}
+
+// DecryptDPAPIWithOptions decrypts data using DPAPI with the scope, secondary
+// entropy, and flags described by opts. It returns the decrypted data, the
+// description embedded at encryption time, and any error encountered.
+func DecryptDPAPIWithOptions(data []byte, opts Options) ([]byte, string, error) {
+	if len(data) == 0 {
+		return nil, "", fmt.Errorf("input data cannot be empty")
+	}
+
+	if verboseLogging {
+		log.Println("Decrypting data using DPAPI with options...")
+	}
+
+	inBlob := windows.DataBlob{
+		Size: uint32(len(data)),
+		Data: &data[0],
+	}
+	var outBlob windows.DataBlob
+	var desc *uint16
+
+	err := windows.CryptUnprotectData(
+		&inBlob,
+		&desc,
+		opts.entropyBlob(),
+		0,
+		nil,
+		opts.flags(),
+		&outBlob,
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decrypt data: %w", err)
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(outBlob.Data)))
+	if desc != nil {
+		defer windows.LocalFree(windows.Handle(unsafe.Pointer(desc)))
+	}
+
+	// Create a copy of the decrypted data
+	decrypted := make([]byte, outBlob.Size)
+	copy(decrypted, unsafe.Slice(outBlob.Data, outBlob.Size))
+
+	var description string
+	if desc != nil {
+		description = windows.UTF16PtrToString(desc)
+	}
+
+	if verboseLogging {
+		log.Println("Data decryption successful.")
+	}
+	return decrypted, description, nil
+}
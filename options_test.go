@@ -0,0 +1,43 @@
+package dpapi
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncryptDecryptDPAPIWithOptionsEntropyAndDescription(t *testing.T) {
+	originalData := []byte("Hello DPAPI options!")
+	opts := Options{
+		Scope:       ScopeCurrentUser,
+		Entropy:     []byte("extra entropy"),
+		Description: "test description",
+	}
+
+	encrypted, err := EncryptDPAPIWithOptions(originalData, opts)
+	if err != nil {
+		t.Fatalf("failed to encrypt data: %v", err)
+	}
+
+	decrypted, description, err := DecryptDPAPIWithOptions(encrypted, opts)
+	if err != nil {
+		t.Fatalf("failed to decrypt data: %v", err)
+	}
+	if !bytes.Equal(originalData, decrypted) {
+		t.Errorf("decrypted data does not match original.\nGot:  %v\nWant: %v", decrypted, originalData)
+	}
+	if description != opts.Description {
+		t.Errorf("expected description %q, got %q", opts.Description, description)
+	}
+}
+
+func TestDecryptDPAPIWithOptionsRejectsMismatchedEntropy(t *testing.T) {
+	originalData := []byte("Hello DPAPI options!")
+	encrypted, err := EncryptDPAPIWithOptions(originalData, Options{Entropy: []byte("right entropy")})
+	if err != nil {
+		t.Fatalf("failed to encrypt data: %v", err)
+	}
+
+	if _, _, err := DecryptDPAPIWithOptions(encrypted, Options{Entropy: []byte("wrong entropy")}); err == nil {
+		t.Error("expected error when decrypting with mismatched entropy, but got nil")
+	}
+}
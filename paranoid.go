@@ -0,0 +1,273 @@
+package dpapi
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+
+	"github.com/aead/serpent"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/sha3"
+)
+
+// paranoidMagic identifies a password-wrapped, DPAPI-cascaded container.
+const paranoidMagic = "DPAPIPARA"
+
+// paranoidVersion is the current paranoid container version.
+const paranoidVersion uint32 = 1
+
+const (
+	paranoidSaltSize  = 16
+	paranoidNonceSize = 24
+	paranoidMACSize   = blake2b.Size256
+)
+
+// ParanoidOptions tunes the Argon2id pass used to derive key material for
+// EncryptDPAPIWithPassword. A nil *ParanoidOptions uses DefaultParanoidOptions.
+type ParanoidOptions struct {
+	// Argon2Time is the number of Argon2id iterations.
+	Argon2Time uint32
+	// Argon2MemoryKiB is the Argon2id memory cost in KiB.
+	Argon2MemoryKiB uint32
+	// Argon2Threads is the Argon2id degree of parallelism.
+	Argon2Threads uint8
+}
+
+// DefaultParanoidOptions matches Picocrypt's paranoid-mode defaults: four
+// passes over 1 GiB of memory using four lanes.
+var DefaultParanoidOptions = ParanoidOptions{
+	Argon2Time:      4,
+	Argon2MemoryKiB: 1 << 20, // 1 GiB
+	Argon2Threads:   4,
+}
+
+// EncryptDPAPIWithPassword cascades ChaCha20 and Serpent-CTR, keyed from an
+// Argon2id-derived passphrase, on top of machine-scope DPAPI protection. An
+// attacker needs both the host's DPAPI key and the passphrase to recover
+// data, unlike plain DPAPI which a machine-local attacker can unprotect
+// outright.
+func EncryptDPAPIWithPassword(data, password []byte, opts *ParanoidOptions) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("input data cannot be empty")
+	}
+	if len(password) == 0 {
+		return nil, fmt.Errorf("password cannot be empty")
+	}
+	if opts == nil {
+		opts = &DefaultParanoidOptions
+	}
+
+	if verboseLogging {
+		log.Println("Encrypting data using password-wrapped DPAPI cascade...")
+	}
+
+	salt := make([]byte, paranoidSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	nonce := make([]byte, paranoidNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	chachaKey, serpentKey, macKey, err := deriveParanoidKeys(password, salt, *opts)
+	if err != nil {
+		return nil, err
+	}
+
+	cascaded, err := paranoidCascadeEncrypt(data, nonce, chachaKey, serpentKey)
+	if err != nil {
+		return nil, err
+	}
+
+	protected, err := EncryptDPAPI(cascaded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to DPAPI-protect cascade output: %w", err)
+	}
+
+	header := encodeParanoidHeader(salt, nonce, *opts)
+
+	mac := paranoidMAC(macKey, header, protected)
+
+	out := make([]byte, 0, len(header)+len(protected)+len(mac))
+	out = append(out, header...)
+	out = append(out, protected...)
+	out = append(out, mac...)
+
+	if verboseLogging {
+		log.Println("Password-wrapped DPAPI cascade encryption successful.")
+	}
+	return out, nil
+}
+
+// DecryptDPAPIWithPassword reverses EncryptDPAPIWithPassword. The MAC is
+// verified before DPAPI is ever invoked, so tampering is rejected quickly
+// instead of surfacing as an opaque DPAPI failure.
+func DecryptDPAPIWithPassword(data, password []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("input data cannot be empty")
+	}
+	if len(password) == 0 {
+		return nil, fmt.Errorf("password cannot be empty")
+	}
+
+	if verboseLogging {
+		log.Println("Decrypting data using password-wrapped DPAPI cascade...")
+	}
+
+	salt, nonce, opts, headerLen, err := decodeParanoidHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < headerLen+paranoidMACSize {
+		return nil, fmt.Errorf("paranoid container truncated")
+	}
+	protected := data[headerLen : len(data)-paranoidMACSize]
+	wantMAC := data[len(data)-paranoidMACSize:]
+
+	chachaKey, serpentKey, macKey, err := deriveParanoidKeys(password, salt, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	gotMAC := paranoidMAC(macKey, data[:headerLen], protected)
+	if subtle.ConstantTimeCompare(gotMAC, wantMAC) != 1 {
+		return nil, fmt.Errorf("paranoid container failed MAC verification: wrong password or tampered data")
+	}
+
+	cascaded, err := DecryptDPAPI(protected)
+	if err != nil {
+		return nil, fmt.Errorf("failed to DPAPI-unprotect cascade output: %w", err)
+	}
+
+	plaintext, err := paranoidCascadeDecrypt(cascaded, nonce, chachaKey, serpentKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if verboseLogging {
+		log.Println("Password-wrapped DPAPI cascade decryption successful.")
+	}
+	return plaintext, nil
+}
+
+// deriveParanoidKeys runs Argon2id over password and salt, then expands the
+// result via HKDF-SHA3 into independent ChaCha20, Serpent, and MAC keys.
+func deriveParanoidKeys(password, salt []byte, opts ParanoidOptions) (chachaKey, serpentKey, macKey []byte, err error) {
+	argonKey := argon2.IDKey(password, salt, opts.Argon2Time, opts.Argon2MemoryKiB, opts.Argon2Threads, 64)
+
+	hash := func() hash.Hash { return sha3.New256() }
+	kdf := hkdf.New(hash, argonKey, salt, []byte("dpapi-paranoid-cascade"))
+
+	chachaKey = make([]byte, chacha20.KeySize)
+	serpentKey = make([]byte, 32)
+	macKey = make([]byte, 32)
+	for _, key := range [][]byte{chachaKey, serpentKey, macKey} {
+		if _, err := io.ReadFull(kdf, key); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to derive key material: %w", err)
+		}
+	}
+	return chachaKey, serpentKey, macKey, nil
+}
+
+// paranoidCascadeEncrypt applies ChaCha20 followed by Serpent-CTR, mirroring
+// Picocrypt's paranoid-mode cascade.
+func paranoidCascadeEncrypt(plaintext, nonce, chachaKey, serpentKey []byte) ([]byte, error) {
+	chachaOut := make([]byte, len(plaintext))
+	chachaCipher, err := chacha20.NewUnauthenticatedCipher(chachaKey, nonce[:chacha20.NonceSize])
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize ChaCha20: %w", err)
+	}
+	chachaCipher.XORKeyStream(chachaOut, plaintext)
+
+	serpentBlock, err := serpent.NewCipher(serpentKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Serpent: %w", err)
+	}
+	out := make([]byte, len(chachaOut))
+	cipher.NewCTR(serpentBlock, nonce[:serpentBlock.BlockSize()]).XORKeyStream(out, chachaOut)
+	return out, nil
+}
+
+// paranoidCascadeDecrypt reverses paranoidCascadeEncrypt: Serpent-CTR first,
+// then ChaCha20.
+func paranoidCascadeDecrypt(ciphertext, nonce, chachaKey, serpentKey []byte) ([]byte, error) {
+	serpentBlock, err := serpent.NewCipher(serpentKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Serpent: %w", err)
+	}
+	serpentOut := make([]byte, len(ciphertext))
+	cipher.NewCTR(serpentBlock, nonce[:serpentBlock.BlockSize()]).XORKeyStream(serpentOut, ciphertext)
+
+	chachaCipher, err := chacha20.NewUnauthenticatedCipher(chachaKey, nonce[:chacha20.NonceSize])
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize ChaCha20: %w", err)
+	}
+	out := make([]byte, len(serpentOut))
+	chachaCipher.XORKeyStream(out, serpentOut)
+	return out, nil
+}
+
+// encodeParanoidHeader serializes the magic, version, salt, nonce, and
+// Argon2 parameters that a decrypting party needs before it can even derive
+// keys.
+func encodeParanoidHeader(salt, nonce []byte, opts ParanoidOptions) []byte {
+	header := make([]byte, 0, len(paranoidMagic)+4+len(salt)+len(nonce)+4+4+1)
+	header = append(header, paranoidMagic...)
+	header = binary.BigEndian.AppendUint32(header, paranoidVersion)
+	header = append(header, salt...)
+	header = append(header, nonce...)
+	header = binary.BigEndian.AppendUint32(header, opts.Argon2Time)
+	header = binary.BigEndian.AppendUint32(header, opts.Argon2MemoryKiB)
+	header = append(header, opts.Argon2Threads)
+	return header
+}
+
+// decodeParanoidHeader parses the header written by encodeParanoidHeader and
+// returns its length so the caller can slice out the ciphertext that
+// follows it.
+func decodeParanoidHeader(data []byte) (salt, nonce []byte, opts ParanoidOptions, headerLen int, err error) {
+	headerLen = len(paranoidMagic) + 4 + paranoidSaltSize + paranoidNonceSize + 4 + 4 + 1
+	if len(data) < headerLen {
+		return nil, nil, ParanoidOptions{}, 0, fmt.Errorf("not a password-wrapped DPAPI container: too short")
+	}
+	if string(data[:len(paranoidMagic)]) != paranoidMagic {
+		return nil, nil, ParanoidOptions{}, 0, fmt.Errorf("not a password-wrapped DPAPI container: bad magic")
+	}
+	offset := len(paranoidMagic)
+	version := binary.BigEndian.Uint32(data[offset : offset+4])
+	if version != paranoidVersion {
+		return nil, nil, ParanoidOptions{}, 0, fmt.Errorf("unsupported paranoid container version: %d", version)
+	}
+	offset += 4
+
+	salt = data[offset : offset+paranoidSaltSize]
+	offset += paranoidSaltSize
+	nonce = data[offset : offset+paranoidNonceSize]
+	offset += paranoidNonceSize
+
+	opts.Argon2Time = binary.BigEndian.Uint32(data[offset : offset+4])
+	offset += 4
+	opts.Argon2MemoryKiB = binary.BigEndian.Uint32(data[offset : offset+4])
+	offset += 4
+	opts.Argon2Threads = data[offset]
+
+	return salt, nonce, opts, headerLen, nil
+}
+
+// paranoidMAC computes a keyed BLAKE2b MAC over header||ciphertext so that
+// decryption can detect tampering before DPAPI ever touches the data.
+func paranoidMAC(macKey, header, ciphertext []byte) []byte {
+	h, _ := blake2b.New256(macKey)
+	h.Write(header)
+	h.Write(ciphertext)
+	return h.Sum(nil)
+}
@@ -0,0 +1,65 @@
+package dpapi
+
+import (
+	"bytes"
+	"testing"
+)
+
+// fastParanoidOptions keeps Argon2id cheap enough for unit tests.
+var fastParanoidOptions = ParanoidOptions{Argon2Time: 1, Argon2MemoryKiB: 64, Argon2Threads: 1}
+
+func TestEncryptDecryptDPAPIWithPassword(t *testing.T) {
+	originalData := []byte("Hello paranoid mode!")
+	password := []byte("correct horse battery staple")
+
+	encrypted, err := EncryptDPAPIWithPassword(originalData, password, &fastParanoidOptions)
+	if err != nil {
+		t.Fatalf("failed to encrypt data: %v", err)
+	}
+	if bytes.Equal(originalData, encrypted) {
+		t.Fatal("expected encrypted data to differ from original data")
+	}
+
+	decrypted, err := DecryptDPAPIWithPassword(encrypted, password)
+	if err != nil {
+		t.Fatalf("failed to decrypt data: %v", err)
+	}
+	if !bytes.Equal(originalData, decrypted) {
+		t.Errorf("decrypted data does not match original.\nGot:  %v\nWant: %v", decrypted, originalData)
+	}
+}
+
+func TestDecryptDPAPIWithPasswordRejectsWrongPassword(t *testing.T) {
+	originalData := []byte("Hello paranoid mode!")
+	encrypted, err := EncryptDPAPIWithPassword(originalData, []byte("right password"), &fastParanoidOptions)
+	if err != nil {
+		t.Fatalf("failed to encrypt data: %v", err)
+	}
+
+	if _, err := DecryptDPAPIWithPassword(encrypted, []byte("wrong password")); err == nil {
+		t.Error("expected error when decrypting with the wrong password, but got nil")
+	}
+}
+
+func TestDecryptDPAPIWithPasswordRejectsTampering(t *testing.T) {
+	originalData := []byte("Hello paranoid mode!")
+	password := []byte("correct horse battery staple")
+	encrypted, err := EncryptDPAPIWithPassword(originalData, password, &fastParanoidOptions)
+	if err != nil {
+		t.Fatalf("failed to encrypt data: %v", err)
+	}
+
+	encrypted[len(encrypted)-1] ^= 0xFF
+	if _, err := DecryptDPAPIWithPassword(encrypted, password); err == nil {
+		t.Error("expected error when decrypting tampered data, but got nil")
+	}
+}
+
+func TestEncryptDPAPIWithPasswordRejectsEmptyInput(t *testing.T) {
+	if _, err := EncryptDPAPIWithPassword(nil, []byte("password"), &fastParanoidOptions); err == nil {
+		t.Error("expected error when encrypting empty data, but got nil")
+	}
+	if _, err := EncryptDPAPIWithPassword([]byte("data"), nil, &fastParanoidOptions); err == nil {
+		t.Error("expected error when encrypting with an empty password, but got nil")
+	}
+}
@@ -0,0 +1,142 @@
+package dpapi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"testing"
+)
+
+func TestEncryptFileWithOptionsReedSolomonRoundTrip(t *testing.T) {
+	inputFile, err := os.CreateTemp("", "testfile*.txt")
+	if err != nil {
+		t.Fatalf("failed to create temporary input file: %v", err)
+	}
+	defer os.Remove(inputFile.Name())
+
+	originalData := bytes.Repeat([]byte("Reed-Solomon protected DPAPI data. "), 2000)
+	if _, err := inputFile.Write(originalData); err != nil {
+		t.Fatalf("failed to write to temporary input file: %v", err)
+	}
+	inputFile.Close()
+
+	outputFile := inputFile.Name() + ".enc"
+	if err := EncryptFileWithOptions(inputFile.Name(), outputFile, EncryptFileOptions{ReedSolomon: true}); err != nil {
+		t.Fatalf("failed to encrypt file with Reed-Solomon: %v", err)
+	}
+	defer os.Remove(outputFile)
+
+	encoded, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("failed to read encrypted file: %v", err)
+	}
+	if len(encoded) < len(rsMagic) || string(encoded[:len(rsMagic)]) != rsMagic {
+		t.Fatalf("expected output file to start with Reed-Solomon magic")
+	}
+
+	decryptedFile := inputFile.Name() + ".dec"
+	if err := DecryptFile(outputFile, decryptedFile); err != nil {
+		t.Fatalf("failed to decrypt Reed-Solomon protected file: %v", err)
+	}
+	defer os.Remove(decryptedFile)
+
+	decryptedData, err := os.ReadFile(decryptedFile)
+	if err != nil {
+		t.Fatalf("failed to read decrypted file: %v", err)
+	}
+	if !bytes.Equal(originalData, decryptedData) {
+		t.Error("decrypted Reed-Solomon file data does not match original")
+	}
+}
+
+func TestRepairFileRecoversCorruptShard(t *testing.T) {
+	inputFile, err := os.CreateTemp("", "testfile*.txt")
+	if err != nil {
+		t.Fatalf("failed to create temporary input file: %v", err)
+	}
+	defer os.Remove(inputFile.Name())
+
+	originalData := bytes.Repeat([]byte("recoverable "), 5000)
+	if _, err := inputFile.Write(originalData); err != nil {
+		t.Fatalf("failed to write to temporary input file: %v", err)
+	}
+	inputFile.Close()
+
+	outputFile := inputFile.Name() + ".enc"
+	if err := EncryptFileWithOptions(inputFile.Name(), outputFile, EncryptFileOptions{ReedSolomon: true}); err != nil {
+		t.Fatalf("failed to encrypt file with Reed-Solomon: %v", err)
+	}
+	defer os.Remove(outputFile)
+
+	// Flip a byte inside the first shard's payload to simulate a bad sector.
+	data, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("failed to read encrypted file: %v", err)
+	}
+	corruptOffset := len(rsMagic) + 4 + binary.Size(rsHeader{}) + 4 + 4 + 10 // past magic+version+header+block length+shard checksum, into first shard payload
+	if corruptOffset >= len(data) {
+		t.Fatal("test file too small to corrupt a shard")
+	}
+	data[corruptOffset] ^= 0xFF
+	if err := os.WriteFile(outputFile, data, 0644); err != nil {
+		t.Fatalf("failed to write corrupted file: %v", err)
+	}
+
+	if err := RepairFile(outputFile); err != nil {
+		t.Fatalf("failed to repair corrupted file: %v", err)
+	}
+
+	decryptedFile := inputFile.Name() + ".dec"
+	if err := DecryptFile(outputFile, decryptedFile); err != nil {
+		t.Fatalf("failed to decrypt repaired file: %v", err)
+	}
+	defer os.Remove(decryptedFile)
+
+	decryptedData, err := os.ReadFile(decryptedFile)
+	if err != nil {
+		t.Fatalf("failed to read decrypted file: %v", err)
+	}
+	if !bytes.Equal(originalData, decryptedData) {
+		t.Error("decrypted repaired file data does not match original")
+	}
+}
+
+func TestDecryptFileRejectsBogusReedSolomonHeader(t *testing.T) {
+	var container bytes.Buffer
+	container.WriteString(rsMagic)
+	binary.Write(&container, binary.BigEndian, rsVersion)
+	binary.Write(&container, binary.BigEndian, rsHeader{
+		DataShards:   rsDataShards,
+		ParityShards: rsParityShards,
+		ShardSize:    0xFFFFFFF0, // bogus: would size a multi-gigabyte allocation per shard
+	})
+
+	inputFile, err := os.CreateTemp("", "bogusrs*.bin")
+	if err != nil {
+		t.Fatalf("failed to create temporary input file: %v", err)
+	}
+	defer os.Remove(inputFile.Name())
+	if _, err := inputFile.Write(container.Bytes()); err != nil {
+		t.Fatalf("failed to write temporary input file: %v", err)
+	}
+	inputFile.Close()
+
+	decryptedFile := inputFile.Name() + ".dec"
+	if err := DecryptFile(inputFile.Name(), decryptedFile); err == nil {
+		t.Fatal("expected error decrypting a file with a bogus Reed-Solomon header, but got nil")
+	}
+}
+
+func TestRepairFileRejectsNonReedSolomonFile(t *testing.T) {
+	inputFile, err := os.CreateTemp("", "testfile*.txt")
+	if err != nil {
+		t.Fatalf("failed to create temporary input file: %v", err)
+	}
+	defer os.Remove(inputFile.Name())
+	inputFile.WriteString("plain DPAPI container, no Reed-Solomon")
+	inputFile.Close()
+
+	if err := RepairFile(inputFile.Name()); err == nil {
+		t.Error("expected error when repairing a non-Reed-Solomon file, but got nil")
+	}
+}
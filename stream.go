@@ -0,0 +1,367 @@
+package dpapi
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// streamMagic identifies a chunked DPAPI stream container. It is followed by
+// a uint32 version and a flags byte, for a 16-byte header (see
+// writeStreamHeader).
+const streamMagic = "DPAPISTRM"
+
+// streamVersion is the current chunked stream container version.
+const streamVersion uint32 = 1
+
+// streamFlagArchive marks a stream container whose plaintext is a zip
+// archive that DecryptPath should extract rather than write out verbatim.
+const streamFlagArchive byte = 1 << 0
+
+// streamChunkSize is the size of each plaintext chunk that is DPAPI-protected
+// independently. Keeping chunks small bounds both DPAPI's own practical size
+// limits and the peak memory used while streaming.
+const streamChunkSize = 1 << 20 // 1 MiB
+
+// EncryptStream reads plaintext from r, DPAPI-protects it in fixed-size
+// chunks, and writes the framed, encrypted container to w.
+func EncryptStream(r io.Reader, w io.Writer) error {
+	return EncryptStreamWithProgress(r, w, nil)
+}
+
+// EncryptStreamWithProgress behaves like EncryptStream but additionally
+// reports cumulative plaintext bytes processed to progress, if non-nil.
+func EncryptStreamWithProgress(r io.Reader, w io.Writer, progress io.Writer) error {
+	return encryptStreamFlags(r, w, 0, progress)
+}
+
+// encryptStreamFlags is EncryptStreamWithProgress with header flags exposed
+// for EncryptPath to mark archive payloads.
+func encryptStreamFlags(r io.Reader, w io.Writer, flags byte, progress io.Writer) error {
+	if verboseLogging {
+		log.Println("Encrypting stream using DPAPI...")
+	}
+
+	if err := writeStreamHeader(w, flags); err != nil {
+		return err
+	}
+
+	chunk := make([]byte, streamChunkSize)
+	var total int64
+	for {
+		n, readErr := io.ReadFull(r, chunk)
+		if n > 0 {
+			encrypted, err := EncryptDPAPI(chunk[:n])
+			if err != nil {
+				return fmt.Errorf("failed to encrypt chunk: %w", err)
+			}
+			if err := writeStreamFrame(w, encrypted); err != nil {
+				return err
+			}
+			total += int64(n)
+			if progress != nil {
+				fmt.Fprintf(progress, "%d bytes encrypted\n", total)
+			}
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return fmt.Errorf("failed to read input stream: %w", readErr)
+		}
+	}
+
+	// Terminate the frame sequence with a zero-length record.
+	if err := writeStreamFrame(w, nil); err != nil {
+		return err
+	}
+
+	if verboseLogging {
+		log.Println("Stream encryption successful.")
+	}
+	return nil
+}
+
+// DecryptStream reads a framed DPAPI stream container from r, DPAPI-unprotects
+// each chunk, and writes the recovered plaintext to w.
+func DecryptStream(r io.Reader, w io.Writer) error {
+	return DecryptStreamWithProgress(r, w, nil)
+}
+
+// DecryptStreamWithProgress behaves like DecryptStream but additionally
+// reports cumulative plaintext bytes recovered to progress, if non-nil.
+func DecryptStreamWithProgress(r io.Reader, w io.Writer, progress io.Writer) error {
+	if verboseLogging {
+		log.Println("Decrypting stream using DPAPI...")
+	}
+
+	if _, err := readStreamHeader(r); err != nil {
+		return err
+	}
+	if err := decryptStreamFrames(r, w, progress); err != nil {
+		return err
+	}
+
+	if verboseLogging {
+		log.Println("Stream decryption successful.")
+	}
+	return nil
+}
+
+// decryptStreamFrames decrypts the chunk frames that follow a stream
+// header, which the caller must already have consumed via readStreamHeader.
+// Splitting the header read out lets DecryptPath inspect the archive flag
+// before picking a destination writer for the frames.
+func decryptStreamFrames(r io.Reader, w io.Writer, progress io.Writer) error {
+	var total int64
+	for {
+		frame, err := readStreamFrame(r)
+		if err != nil {
+			return err
+		}
+		if len(frame) == 0 {
+			break
+		}
+
+		decrypted, err := DecryptDPAPI(frame)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt chunk: %w", err)
+		}
+		if _, err := w.Write(decrypted); err != nil {
+			return fmt.Errorf("failed to write decrypted chunk: %w", err)
+		}
+		total += int64(len(decrypted))
+		if progress != nil {
+			fmt.Fprintf(progress, "%d bytes decrypted\n", total)
+		}
+	}
+	return nil
+}
+
+// writeStreamHeader writes the 16-byte magic+version+flags header for a
+// stream container: 9 bytes magic, a uint32 version, a flags byte, and two
+// reserved bytes.
+func writeStreamHeader(w io.Writer, flags byte) error {
+	header := make([]byte, 16)
+	copy(header, streamMagic)
+	binary.BigEndian.PutUint32(header[9:13], streamVersion)
+	header[13] = flags
+	if _, err := w.Write(header); err != nil {
+		return fmt.Errorf("failed to write stream header: %w", err)
+	}
+	return nil
+}
+
+// readStreamHeader reads and validates the 16-byte header for a stream
+// container, returning its flags byte.
+func readStreamHeader(r io.Reader) (byte, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, fmt.Errorf("failed to read stream header: %w", err)
+	}
+	if string(header[:9]) != streamMagic {
+		return 0, fmt.Errorf("not a DPAPI stream container: bad magic")
+	}
+	version := binary.BigEndian.Uint32(header[9:13])
+	if version != streamVersion {
+		return 0, fmt.Errorf("unsupported DPAPI stream version: %d", version)
+	}
+	return header[13], nil
+}
+
+// writeStreamFrame writes a single [uint32 big-endian length][blob] record.
+// A nil or empty blob writes the zero-length terminator record.
+func writeStreamFrame(w io.Writer, blob []byte) error {
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(blob)))
+	if _, err := w.Write(length[:]); err != nil {
+		return fmt.Errorf("failed to write frame length: %w", err)
+	}
+	if len(blob) == 0 {
+		return nil
+	}
+	if _, err := w.Write(blob); err != nil {
+		return fmt.Errorf("failed to write frame data: %w", err)
+	}
+	return nil
+}
+
+// readStreamFrame reads a single [uint32 big-endian length][blob] record. A
+// zero-length record is returned as a nil slice with no error.
+func readStreamFrame(r io.Reader) ([]byte, error) {
+	var length [4]byte
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return nil, fmt.Errorf("failed to read frame length: %w", err)
+	}
+	n := binary.BigEndian.Uint32(length[:])
+	if n == 0 {
+		return nil, nil
+	}
+	blob := make([]byte, n)
+	if _, err := io.ReadFull(r, blob); err != nil {
+		return nil, fmt.Errorf("failed to read frame data: %w", err)
+	}
+	return blob, nil
+}
+
+// EncryptFile encrypts the contents of a file and writes the encrypted data to the same file or a new file.
+// If outputFile is an empty string, the inputFile is overwritten. The output
+// is wrapped in an authenticated container (see format.go) so DecryptFile
+// can tell a wrong-format file from a tampered one.
+func EncryptFile(inputFile, outputFile string) error {
+	if outputFile == "" {
+		outputFile = inputFile
+	}
+
+	if verboseLogging {
+		log.Printf("Opening file: %s\n", inputFile)
+	}
+	in, err := os.Open(inputFile)
+	if err != nil {
+		return fmt.Errorf("failed to open input file: %w", err)
+	}
+	defer in.Close()
+
+	if verboseLogging {
+		log.Println("Encrypting file contents...")
+	}
+	if err := streamToFile(outputFile, in, func(w io.Writer) error {
+		payloadWriter, finish, err := beginAuthenticatedFile(w, 0)
+		if err != nil {
+			return err
+		}
+		if err := EncryptStream(in, payloadWriter); err != nil {
+			return err
+		}
+		return finish()
+	}); err != nil {
+		return fmt.Errorf("failed to encrypt file data: %w", err)
+	}
+
+	if verboseLogging {
+		log.Println("File encryption successful.")
+	}
+	return nil
+}
+
+// DecryptFile decrypts the contents of a file and writes the decrypted data to the same file or a new file.
+// If outputFile is an empty string, the inputFile is overwritten.
+func DecryptFile(inputFile, outputFile string) error {
+	if outputFile == "" {
+		outputFile = inputFile
+	}
+
+	if verboseLogging {
+		log.Printf("Opening file: %s\n", inputFile)
+	}
+	in, err := os.Open(inputFile)
+	if err != nil {
+		return fmt.Errorf("failed to open input file: %w", err)
+	}
+	defer in.Close()
+
+	reader, err := openDecryptReader(in)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt file data: %w", err)
+	}
+
+	if verboseLogging {
+		log.Println("Decrypting file contents...")
+	}
+	if err := streamToFile(outputFile, in, func(w io.Writer) error {
+		return DecryptStream(reader, w)
+	}); err != nil {
+		return fmt.Errorf("failed to decrypt file data: %w", err)
+	}
+
+	if verboseLogging {
+		log.Println("File decryption successful.")
+	}
+	return nil
+}
+
+// openDecryptReader returns a reader over in's chunked stream container. It
+// transparently reconstructs the payload first if in holds a Reed-Solomon
+// protected container, and verifies the authenticated file header (if
+// present) before returning the inner stream container.
+func openDecryptReader(in *os.File) (io.Reader, error) {
+	br := bufio.NewReader(in)
+
+	peekLen := len(rsMagic)
+	if len(fileMagic) > peekLen {
+		peekLen = len(fileMagic)
+	}
+	if len(streamMagic) > peekLen {
+		peekLen = len(streamMagic)
+	}
+	peek, _ := br.Peek(peekLen)
+
+	if len(peek) >= len(rsMagic) && string(peek[:len(rsMagic)]) == rsMagic {
+		if verboseLogging {
+			log.Println("Reed-Solomon container detected; reconstructing before decryption...")
+		}
+		// Reconstruct one block at a time into a pipe instead of buffering
+		// the whole container, so a Reed-Solomon protected file doesn't
+		// defeat the bounded-memory goal the rest of this package relies on.
+		pr, pw := io.Pipe()
+		go func() {
+			if err := reedSolomonDecodeStream(br, pw); err != nil {
+				pw.CloseWithError(fmt.Errorf("failed to reconstruct Reed-Solomon container: %w", err))
+				return
+			}
+			pw.Close()
+		}()
+		return pr, nil
+	}
+
+	if len(peek) >= len(fileMagic) && string(peek[:len(fileMagic)]) == fileMagic {
+		if verboseLogging {
+			log.Println("Verifying DPAPI file container before decryption...")
+		}
+		return verifyAuthenticatedFile(in, br)
+	}
+
+	if len(peek) >= len(streamMagic) && string(peek[:len(streamMagic)]) == streamMagic {
+		return br, nil
+	}
+
+	return nil, ErrNotDPAPIFile
+}
+
+// streamToFile runs write against a temporary file next to outputFile and,
+// on success, renames it into place. This lets outputFile equal the file
+// being read without the reader and writer aliasing the same file handle.
+// If closeBeforeRename is non-nil, it is closed once write succeeds and
+// before the rename: on Windows, renaming over a file that a still-open
+// handle holds open (as an input file passed to write's reader would be)
+// fails with a sharing violation, so the input handle must be released
+// first. Callers with no such handle to release pass nil.
+func streamToFile(outputFile string, closeBeforeRename io.Closer, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(outputFile), ".dpapi-tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary output file: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary output file: %w", err)
+	}
+	if closeBeforeRename != nil {
+		if err := closeBeforeRename.Close(); err != nil {
+			return fmt.Errorf("failed to close input file: %w", err)
+		}
+	}
+	if err := os.Rename(tmpName, outputFile); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	return nil
+}
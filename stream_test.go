@@ -0,0 +1,62 @@
+package dpapi
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEncryptDecryptStream(t *testing.T) {
+	original := bytes.Repeat([]byte("Hello DPAPI Stream! "), 1000)
+
+	var encrypted bytes.Buffer
+	if err := EncryptStream(bytes.NewReader(original), &encrypted); err != nil {
+		t.Fatalf("failed to encrypt stream: %v", err)
+	}
+	if encrypted.Len() == 0 {
+		t.Fatal("expected encrypted stream to be non-empty")
+	}
+
+	var decrypted bytes.Buffer
+	if err := DecryptStream(bytes.NewReader(encrypted.Bytes()), &decrypted); err != nil {
+		t.Fatalf("failed to decrypt stream: %v", err)
+	}
+	if !bytes.Equal(original, decrypted.Bytes()) {
+		t.Errorf("decrypted stream does not match original.\nGot:  %v\nWant: %v", decrypted.Bytes(), original)
+	}
+}
+
+func TestEncryptDecryptStreamMultiChunk(t *testing.T) {
+	original := bytes.Repeat([]byte{0x42}, streamChunkSize*2+123)
+
+	var encrypted bytes.Buffer
+	if err := EncryptStream(bytes.NewReader(original), &encrypted); err != nil {
+		t.Fatalf("failed to encrypt stream: %v", err)
+	}
+
+	var decrypted bytes.Buffer
+	if err := DecryptStream(bytes.NewReader(encrypted.Bytes()), &decrypted); err != nil {
+		t.Fatalf("failed to decrypt stream: %v", err)
+	}
+	if !bytes.Equal(original, decrypted.Bytes()) {
+		t.Error("decrypted multi-chunk stream does not match original")
+	}
+}
+
+func TestEncryptStreamWithProgress(t *testing.T) {
+	original := []byte("progress please")
+
+	var encrypted, progress bytes.Buffer
+	if err := EncryptStreamWithProgress(bytes.NewReader(original), &encrypted, &progress); err != nil {
+		t.Fatalf("failed to encrypt stream: %v", err)
+	}
+	if !strings.Contains(progress.String(), "bytes encrypted") {
+		t.Errorf("expected progress output, got %q", progress.String())
+	}
+}
+
+func TestDecryptStreamRejectsBadHeader(t *testing.T) {
+	if err := DecryptStream(strings.NewReader("not a dpapi stream"), &bytes.Buffer{}); err == nil {
+		t.Error("expected error when decrypting a stream with a bad header, but got nil")
+	}
+}